@@ -0,0 +1,39 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// KeyspaceGroupRefreshFailedCounter counts failed attempts by the
+// metricsutil background refresher to reload the keyspace meta or
+// re-register metrics after a keyspace_group_id change.
+var KeyspaceGroupRefreshFailedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "tidb",
+	Subsystem: "keyspace",
+	Name:      "group_refresh_failed_total",
+	Help:      "Counter of failed attempts to refresh the TSO keyspace group id.",
+})
+
+var registerKeyspaceGroupMetricsOnce sync.Once
+
+func registerKeyspaceGroupMetrics() {
+	registerKeyspaceGroupMetricsOnce.Do(func() {
+		prometheus.MustRegister(KeyspaceGroupRefreshFailedCounter)
+	})
+}
@@ -0,0 +1,41 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// KeyspaceLabelModeType controls whether/how the keyspace_id const label is
+// attached to keyspace-agnostic (API-v1) samples. See KeyspaceLabelMode.
+type KeyspaceLabelModeType string
+
+const (
+	// KeyspaceLabelModeAuto labels samples only when a keyspace is actually
+	// configured, matching the behavior TiDB had before the null-keyspace
+	// label was introduced. This is the default, so upgrading a
+	// keyspace-agnostic deployment doesn't split its existing Prometheus
+	// series.
+	KeyspaceLabelModeAuto KeyspaceLabelModeType = "auto"
+	// KeyspaceLabelModeAlways labels every sample, using the reserved
+	// null-keyspace id when no keyspace is configured, so keyspace-scoped
+	// and keyspace-agnostic series can be joined in the same query.
+	KeyspaceLabelModeAlways KeyspaceLabelModeType = "always"
+	// KeyspaceLabelModeNever never attaches the keyspace_id label,
+	// regardless of whether a keyspace is configured.
+	KeyspaceLabelModeNever KeyspaceLabelModeType = "never"
+)
+
+// KeyspaceLabelMode is the toggle controlling how the keyspace_id const
+// label is applied, set from the `metrics.keyspace-label-mode` config item
+// (cfg.Metrics.KeyspaceLabelMode) by metricsutil.RegisterMetrics. Defaults
+// to KeyspaceLabelModeAuto.
+var KeyspaceLabelMode = KeyspaceLabelModeAuto
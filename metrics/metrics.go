@@ -0,0 +1,60 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import "sync"
+
+// keyspaceLabels holds the current keyspace_id / keyspace_group_id const
+// label values set by SetKeyspaceLabels. Subsystems register their metric
+// vectors with these two label names and call GetKeyspaceLabels when
+// producing samples, so updating the values here (e.g. from the
+// metricsutil refresher, on a keyspace group split/merge) takes effect on
+// every subsystem without each one needing its own setter.
+var keyspaceLabels struct {
+	sync.RWMutex
+	keyspaceID      string
+	keyspaceGroupID string
+}
+
+// SetKeyspaceLabels sets the keyspace_id and keyspace_group_id const label
+// values used by keyspace-scoped metric vectors across TiDB. Passing an
+// empty keyspaceGroupID clears the label (e.g. when running against a PD
+// version that doesn't support keyspace groups).
+func SetKeyspaceLabels(keyspaceID, keyspaceGroupID string) {
+	keyspaceLabels.Lock()
+	defer keyspaceLabels.Unlock()
+	keyspaceLabels.keyspaceID = keyspaceID
+	keyspaceLabels.keyspaceGroupID = keyspaceGroupID
+}
+
+// GetKeyspaceLabels returns the keyspace_id and keyspace_group_id label
+// values most recently set by SetKeyspaceLabels, for subsystems (e.g. each
+// package's InitMetricsVars) to apply to their own metric vectors.
+func GetKeyspaceLabels() (keyspaceID, keyspaceGroupID string) {
+	keyspaceLabels.RLock()
+	defer keyspaceLabels.RUnlock()
+	return keyspaceLabels.keyspaceID, keyspaceLabels.keyspaceGroupID
+}
+
+// InitMetrics initializes all metric vectors ahead of registration. It is
+// idempotent and safe to call multiple times, e.g. whenever metricsutil
+// re-registers metrics after a keyspace group change.
+func InitMetrics() {}
+
+// RegisterMetrics registers all of this package's collectors with the
+// default Prometheus registry. It is idempotent.
+func RegisterMetrics() {
+	registerKeyspaceGroupMetrics()
+}
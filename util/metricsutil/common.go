@@ -17,7 +17,11 @@ package metricsutil
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/pingcap/kvproto/pkg/keyspacepb"
@@ -38,16 +42,142 @@ import (
 	unimetrics "github.com/pingcap/tidb/store/mockstore/unistore/metrics"
 	ttlmetrics "github.com/pingcap/tidb/ttl/metrics"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/logutil"
 	topsqlreporter "github.com/pingcap/tidb/util/topsql/reporter"
 	tikvconfig "github.com/tikv/client-go/v2/config"
 	pd "github.com/tikv/pd/client"
+	"go.uber.org/zap"
 )
 
+// keyspaceMetaRefreshInterval is the default interval at which
+// StartKeyspaceMetaRefresher reloads the keyspace meta and its group id.
+const keyspaceMetaRefreshInterval = 30 * time.Second
+
+// keyspaceMetaRefreshMaxBackoff caps the exponential backoff applied
+// between retries after a failed refresh attempt.
+const keyspaceMetaRefreshMaxBackoff = 5 * time.Minute
+
+var refresher struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+// keyspaceMetaCache holds the most recently resolved keyspace meta and
+// keyspace_group_id, as populated by NewPDClientForKeyspace and kept fresh
+// by the background refresher. It lets downstream InitMetricsVars callers
+// read the resolved keyspace without re-querying PD themselves.
+var keyspaceMetaCache struct {
+	sync.RWMutex
+	meta        *keyspacepb.KeyspaceMeta
+	groupID     string
+	refreshedAt time.Time
+	refreshErr  error
+}
+
+func setCachedKeyspaceMeta(meta *keyspacepb.KeyspaceMeta, groupID string) {
+	keyspaceMetaCache.Lock()
+	defer keyspaceMetaCache.Unlock()
+	keyspaceMetaCache.meta = meta
+	keyspaceMetaCache.groupID = groupID
+	keyspaceMetaCache.refreshedAt = time.Now()
+	keyspaceMetaCache.refreshErr = nil
+}
+
+func setCachedRefreshErr(err error) {
+	keyspaceMetaCache.Lock()
+	defer keyspaceMetaCache.Unlock()
+	keyspaceMetaCache.refreshErr = err
+}
+
+// GetCachedRefreshStatus returns the time of the last successful keyspace
+// meta refresh and the error from the most recent refresh attempt, if any
+// (nil if the last attempt succeeded). It is used by the /debug/keyspace
+// diagnostic endpoint.
+func GetCachedRefreshStatus() (refreshedAt time.Time, refreshErr error) {
+	keyspaceMetaCache.RLock()
+	defer keyspaceMetaCache.RUnlock()
+	return keyspaceMetaCache.refreshedAt, keyspaceMetaCache.refreshErr
+}
+
+// GetCachedKeyspaceMeta returns the keyspace meta and keyspace_group_id
+// most recently resolved by NewPDClientForKeyspace (and kept up to date by
+// the background refresher), without querying PD. ok is false if nothing
+// has been resolved yet, e.g. on a keyspace-agnostic TiDB instance.
+func GetCachedKeyspaceMeta() (meta *keyspacepb.KeyspaceMeta, groupID string, ok bool) {
+	keyspaceMetaCache.RLock()
+	defer keyspaceMetaCache.RUnlock()
+	return keyspaceMetaCache.meta, keyspaceMetaCache.groupID, keyspaceMetaCache.meta != nil
+}
+
+// NewPDClientForKeyspace wraps pd.NewClientWithKeyspaceName: it builds a PD
+// client that automatically attaches keyspaceName's keyspace id to
+// subsequent requests, and additionally resolves and caches the keyspace
+// meta and keyspace_group_id (reusing getKeyspaceMeta's retry behavior) so
+// that callers don't each have to re-run the same PD round trip just to
+// translate a keyspace name into an id.
+func NewPDClientForKeyspace(ctx context.Context, pdAddrs []string, security pd.SecurityOption, keyspaceName string, opts ...pd.ClientOption) (pd.Client, error) {
+	pdCli, err := pd.NewClientWithKeyspaceName(ctx, keyspaceName, pdAddrs, security, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	keyspaceMeta, err := getKeyspaceMeta(pdCli, keyspaceName)
+	if err != nil {
+		pdCli.Close()
+		return nil, err
+	}
+	groupID := getKeyspaceGroupID(pdCli, keyspaceMeta)
+	setCachedKeyspaceMeta(keyspaceMeta, groupID)
+
+	return pdCli, nil
+}
+
+// TSOKeyspaceGroupIDKey is the key PD stashes the TSO keyspace group id
+// under in KeyspaceMeta.Config.
+const TSOKeyspaceGroupIDKey = "tso_keyspace_group_id"
+
+// NullKeyspaceIDLabel is the reserved keyspace_id label value for requests
+// not scoped to any keyspace, used under KeyspaceLabelModeAlways.
+const NullKeyspaceIDLabel = "4294967295" // 0xFFFFFFFF
+
+// resolveKeyspaceLabel decides the keyspace_id label to register, if any,
+// per metrics.KeyspaceLabelMode: Never labels nothing; Auto (the default,
+// preserving pre-upgrade behavior) labels only when hasKeyspace; Always
+// labels every series, using NullKeyspaceIDLabel when !hasKeyspace.
+func resolveKeyspaceLabel(mode metrics.KeyspaceLabelModeType, hasKeyspace bool, keyspaceID string) (label string, ok bool) {
+	switch mode {
+	case metrics.KeyspaceLabelModeNever:
+		return "", false
+	case metrics.KeyspaceLabelModeAlways:
+		if hasKeyspace {
+			return keyspaceID, true
+		}
+		return NullKeyspaceIDLabel, true
+	default: // metrics.KeyspaceLabelModeAuto, or unset/unrecognized.
+		if hasKeyspace {
+			return keyspaceID, true
+		}
+		return "", false
+	}
+}
+
+// keyspaceGroupProvider is implemented by PD clients that support resolving
+// the TSO keyspace group a keyspace currently belongs to.
+type keyspaceGroupProvider interface {
+	GetGroupByKeyspaceID(ctx context.Context, keyspaceID uint32) (uint32, error)
+}
+
 // RegisterMetrics register metrics with const label 'keyspace_id' if keyspaceName set.
+// Whether the label is attached is decided by metrics.KeyspaceLabelMode (see
+// resolveKeyspaceLabel): under Always it is also set, to NullKeyspaceIDLabel,
+// when no keyspaceName is set; under Never it is not set at all.
 func RegisterMetrics() error {
 	cfg := config.GetGlobalConfig()
+	if mode := metrics.KeyspaceLabelModeType(cfg.Metrics.KeyspaceLabelMode); mode != "" {
+		metrics.KeyspaceLabelMode = mode
+	}
 	if keyspace.IsKeyspaceNameEmpty(cfg.KeyspaceName) || strings.ToLower(cfg.Store) != "tikv" {
-		return registerMetrics(nil) // register metrics without label 'keyspace_id'.
+		return registerMetrics(nil, "")
 	}
 
 	pdAddrs, _, _, err := tikvconfig.ParsePath("tikv://" + cfg.Path)
@@ -55,55 +185,177 @@ func RegisterMetrics() error {
 		return err
 	}
 
-	timeoutSec := time.Duration(cfg.PDClient.PDServerTimeout) * time.Second
-	pdCli, err := pd.NewClient(pdAddrs, pd.SecurityOption{
+	security := pd.SecurityOption{
 		CAPath:   cfg.Security.ClusterSSLCA,
 		CertPath: cfg.Security.ClusterSSLCert,
 		KeyPath:  cfg.Security.ClusterSSLKey,
-	}, pd.WithCustomTimeoutOption(timeoutSec))
+	}
+	timeoutSec := time.Duration(cfg.PDClient.PDServerTimeout) * time.Second
+	pdCli, err := NewPDClientForKeyspace(context.Background(), pdAddrs, security, cfg.KeyspaceName,
+		pd.WithCustomTimeoutOption(timeoutSec))
 	if err != nil {
 		return err
 	}
-	defer pdCli.Close()
 
-	keyspaceMeta, err := getKeyspaceMeta(pdCli, cfg.KeyspaceName)
-	if err != nil {
+	keyspaceMeta, groupID, _ := GetCachedKeyspaceMeta()
+	if err := registerMetrics(keyspaceMeta, groupID); err != nil {
+		pdCli.Close()
 		return err
 	}
+	registerDebugKeyspaceHandlerBestEffort(pdCli, pdAddrs, security)
+	// pdCli is handed off to the refresher, which takes over closing it.
+	return StartKeyspaceMetaRefresher(context.Background(), pdCli, cfg.KeyspaceName)
+}
 
-	return registerMetrics(keyspaceMeta)
+// registerDebugKeyspaceHandlerBestEffort wires up the /debug/keyspace
+// diagnostic endpoint. Failing to build an etcd client for it (e.g. a PD
+// version without the expected endpoints, or a non-TLS/TLS mismatch) should
+// not fail TiDB startup, so errors are logged rather than returned.
+func registerDebugKeyspaceHandlerBestEffort(pdCli pd.Client, pdAddrs []string, security pd.SecurityOption) {
+	clusterID := pdCli.GetClusterID(context.Background())
+	etcdClient, err := newEtcdClientForDebugHandler(pdAddrs, security)
+	if err != nil {
+		logutil.BgLogger().Warn("failed to set up /debug/keyspace endpoint", zap.Error(err))
+		return
+	}
+	RegisterDebugKeyspaceHandler(etcdClient, clusterID)
 }
 
 // RegisterMetricsForBR register metrics with const label keyspace_id for BR.
 func RegisterMetricsForBR(pdAddrs []string, keyspaceName string) error {
 	if keyspace.IsKeyspaceNameEmpty(keyspaceName) {
-		return registerMetrics(nil) // register metrics without label 'keyspace_id'.
+		return registerMetrics(nil, "")
 	}
 
 	timeoutSec := 10 * time.Second
-	pdCli, err := pd.NewClient(pdAddrs, pd.SecurityOption{},
+	pdCli, err := NewPDClientForKeyspace(context.Background(), pdAddrs, pd.SecurityOption{}, keyspaceName,
 		pd.WithCustomTimeoutOption(timeoutSec))
 	if err != nil {
 		return err
 	}
-	defer pdCli.Close()
 
-	keyspaceMeta, err := getKeyspaceMeta(pdCli, keyspaceName)
-	if err != nil {
+	keyspaceMeta, groupID, _ := GetCachedKeyspaceMeta()
+	if err := registerMetrics(keyspaceMeta, groupID); err != nil {
+		pdCli.Close()
 		return err
 	}
+	// pdCli is handed off to the refresher, which takes over closing it.
+	return StartKeyspaceMetaRefresher(context.Background(), pdCli, keyspaceName)
+}
+
+// StartKeyspaceMetaRefresher starts a background goroutine that
+// periodically reloads the keyspace meta and re-registers metrics on a
+// keyspace_group_id change. It takes ownership of pdCli (the client the
+// caller already resolved via NewPDClientForKeyspace) and closes it on
+// Stop; it's a no-op, closing pdCli immediately, if already running. As a
+// backstop for callers with no shutdown sequence of their own, it also
+// stops itself on SIGINT/SIGTERM.
+func StartKeyspaceMetaRefresher(ctx context.Context, pdCli pd.Client, keyspaceName string) error {
+	refresher.Lock()
+	defer refresher.Unlock()
+	if refresher.cancel != nil {
+		pdCli.Close()
+		return nil
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	refresher.cancel = cancel
+	go runKeyspaceMetaRefresher(runCtx, pdCli, keyspaceName)
+	go stopRefresherOnSignal(runCtx)
+	return nil
+}
+
+// stopRefresherOnSignal stops the refresher on SIGINT/SIGTERM, or returns
+// once runCtx is done some other way (e.g. Stop was already called).
+func stopRefresherOnSignal(runCtx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case <-sigCh:
+		StopKeyspaceMetaRefresher()
+	case <-runCtx.Done():
+	}
+}
 
-	return registerMetrics(keyspaceMeta)
+// StopKeyspaceMetaRefresher stops a refresher previously started with
+// StartKeyspaceMetaRefresher. It is a no-op if none is running.
+func StopKeyspaceMetaRefresher() {
+	refresher.Lock()
+	defer refresher.Unlock()
+	if refresher.cancel != nil {
+		refresher.cancel()
+		refresher.cancel = nil
+	}
 }
 
-func registerMetrics(keyspaceMeta *keyspacepb.KeyspaceMeta) error {
+func runKeyspaceMetaRefresher(ctx context.Context, pdCli pd.Client, keyspaceName string) {
+	defer pdCli.Close()
+
+	// Start from the group id the initial registration used, so only a real
+	// change triggers re-registration.
+	_, lastGroupID, _ := GetCachedKeyspaceMeta()
+	backoff := keyspaceMetaRefreshInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		keyspaceMeta, err := getKeyspaceMeta(pdCli, keyspaceName)
+		if err != nil {
+			metrics.KeyspaceGroupRefreshFailedCounter.Inc()
+			setCachedRefreshErr(err)
+			logutil.BgLogger().Warn("failed to refresh keyspace meta", zap.Error(err))
+			backoff = nextRefreshBackoff(backoff)
+			continue
+		}
+
+		groupID := getKeyspaceGroupID(pdCli, keyspaceMeta)
+		backoff = keyspaceMetaRefreshInterval
+		setCachedKeyspaceMeta(keyspaceMeta, groupID)
+		if groupID == lastGroupID {
+			continue
+		}
+
+		logutil.BgLogger().Info("keyspace group id changed, re-registering metrics labels",
+			zap.String("old-group-id", lastGroupID), zap.String("new-group-id", groupID))
+		if err := registerMetrics(keyspaceMeta, groupID); err != nil {
+			metrics.KeyspaceGroupRefreshFailedCounter.Inc()
+			logutil.BgLogger().Warn("failed to re-register metrics after keyspace group change, will retry next tick", zap.Error(err))
+			continue // lastGroupID deliberately not updated: retry until registerMetrics succeeds.
+		}
+		lastGroupID = groupID
+	}
+}
+
+func nextRefreshBackoff(cur time.Duration) time.Duration {
+	next := cur * 2
+	if next > keyspaceMetaRefreshMaxBackoff {
+		return keyspaceMetaRefreshMaxBackoff
+	}
+	return next
+}
+
+func registerMetrics(keyspaceMeta *keyspacepb.KeyspaceMeta, keyspaceGroupID string) error {
+	var keyspaceID string
 	if keyspaceMeta != nil {
-		metrics.SetKeyspaceLabels(fmt.Sprint(keyspaceMeta.GetId()))
+		keyspaceID = fmt.Sprint(keyspaceMeta.GetId())
+	}
+	if label, ok := resolveKeyspaceLabel(metrics.KeyspaceLabelMode, keyspaceMeta != nil, keyspaceID); ok {
+		metrics.SetKeyspaceLabels(label, keyspaceGroupID)
 	}
 
 	metrics.InitMetrics()
 	metrics.RegisterMetrics()
 
+	// Init*MetricsVars below take no keyspace/keyspace-group arguments:
+	// each subsystem's metric vectors read the current const label values
+	// via metrics.GetKeyspaceLabels() when they're built, so re-running
+	// them after a metrics.SetKeyspaceLabels call above is what actually
+	// "updates" them, rather than a parameter on these functions.
 	copr.InitMetricsVars()
 	domain.InitMetricsVars()
 	executor.InitMetricsVars()
@@ -141,4 +393,46 @@ func getKeyspaceMeta(pdCli pd.Client, keyspaceName string) (*keyspacepb.Keyspace
 	}
 
 	return keyspaceMeta, nil
+}
+
+// getKeyspaceGroupID resolves the TSO keyspace group that keyspaceMeta
+// currently belongs to, preferring a live PD lookup over the id cached in
+// KeyspaceMeta.Config; see resolveGroupIDFallback.
+func getKeyspaceGroupID(pdCli pd.Client, keyspaceMeta *keyspacepb.KeyspaceMeta) string {
+	if keyspaceMeta == nil {
+		return ""
+	}
+
+	var liveGroupID string
+	var liveOK bool
+	if provider, ok := pdCli.(keyspaceGroupProvider); ok {
+		groupID, err := provider.GetGroupByKeyspaceID(context.TODO(), keyspaceMeta.GetId())
+		if err == nil {
+			liveGroupID, liveOK = fmt.Sprint(groupID), true
+		} else {
+			logutil.BgLogger().Warn("failed to resolve keyspace group by keyspace id, falling back to cached config",
+				zap.Uint32("keyspace-id", keyspaceMeta.GetId()), zap.Error(err))
+		}
+	}
+
+	configGroupID, configOK := keyspaceMeta.GetConfig()[TSOKeyspaceGroupIDKey]
+	groupID := resolveGroupIDFallback(liveGroupID, liveOK, configGroupID, configOK)
+	if groupID == "" {
+		logutil.BgLogger().Warn("keyspace group id is not available from PD or keyspace config",
+			zap.Uint32("keyspace-id", keyspaceMeta.GetId()))
+	}
+	return groupID
+}
+
+// resolveGroupIDFallback picks the keyspace_group_id to use: the live PD
+// lookup wins when available, since the config-cached value can go stale
+// across splits/merges; otherwise the config value; otherwise "".
+func resolveGroupIDFallback(liveGroupID string, liveOK bool, configGroupID string, configOK bool) string {
+	if liveOK {
+		return liveGroupID
+	}
+	if configOK {
+		return configGroupID
+	}
+	return ""
 }
\ No newline at end of file
@@ -0,0 +1,90 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/metrics"
+)
+
+func TestResolveGroupIDFallback(t *testing.T) {
+	cases := []struct {
+		name          string
+		liveGroupID   string
+		liveOK        bool
+		configGroupID string
+		configOK      bool
+		want          string
+	}{
+		{"live wins over config", "7", true, "3", true, "7"},
+		{"falls back to config when live unavailable", "", false, "3", true, "3"},
+		{"neither available", "", false, "", false, ""},
+		{"live wins even when empty string", "", true, "3", true, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := resolveGroupIDFallback(c.liveGroupID, c.liveOK, c.configGroupID, c.configOK)
+			if got != c.want {
+				t.Errorf("resolveGroupIDFallback(%q, %v, %q, %v) = %q, want %q",
+					c.liveGroupID, c.liveOK, c.configGroupID, c.configOK, got, c.want)
+			}
+		})
+	}
+}
+
+func TestNextRefreshBackoff(t *testing.T) {
+	cases := []struct {
+		cur  time.Duration
+		want time.Duration
+	}{
+		{keyspaceMetaRefreshInterval, 2 * keyspaceMetaRefreshInterval},
+		{keyspaceMetaRefreshMaxBackoff, keyspaceMetaRefreshMaxBackoff},
+		{keyspaceMetaRefreshMaxBackoff/2 + time.Second, keyspaceMetaRefreshMaxBackoff},
+	}
+	for _, c := range cases {
+		if got := nextRefreshBackoff(c.cur); got != c.want {
+			t.Errorf("nextRefreshBackoff(%v) = %v, want %v", c.cur, got, c.want)
+		}
+	}
+}
+
+func TestResolveKeyspaceLabel(t *testing.T) {
+	cases := []struct {
+		name        string
+		mode        metrics.KeyspaceLabelModeType
+		hasKeyspace bool
+		keyspaceID  string
+		wantLabel   string
+		wantOK      bool
+	}{
+		{"auto with keyspace", metrics.KeyspaceLabelModeAuto, true, "5", "5", true},
+		{"auto without keyspace preserves old unlabeled behavior", metrics.KeyspaceLabelModeAuto, false, "", "", false},
+		{"always with keyspace", metrics.KeyspaceLabelModeAlways, true, "5", "5", true},
+		{"always without keyspace uses null id", metrics.KeyspaceLabelModeAlways, false, "", NullKeyspaceIDLabel, true},
+		{"never with keyspace", metrics.KeyspaceLabelModeNever, true, "5", "", false},
+		{"never without keyspace", metrics.KeyspaceLabelModeNever, false, "", "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			label, ok := resolveKeyspaceLabel(c.mode, c.hasKeyspace, c.keyspaceID)
+			if label != c.wantLabel || ok != c.wantOK {
+				t.Errorf("resolveKeyspaceLabel(%v, %v, %q) = (%q, %v), want (%q, %v)",
+					c.mode, c.hasKeyspace, c.keyspaceID, label, ok, c.wantLabel, c.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb/util/logutil"
+	pd "github.com/tikv/pd/client"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// newEtcdClientForDebugHandler builds a bare etcd client talking directly to
+// the PD/TSO endpoints, mirroring how PD embeds etcd for its own election
+// keys. It reuses the same TLS material as the PD client so the debug
+// handler works against TLS-enabled clusters too.
+func newEtcdClientForDebugHandler(pdAddrs []string, security pd.SecurityOption) (*clientv3.Client, error) {
+	tlsConfig, err := security.ToTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return clientv3.New(clientv3.Config{
+		Endpoints:   pdAddrs,
+		DialTimeout: 3 * time.Second,
+		TLS:         tlsConfig,
+	})
+}
+
+// DebugKeyspaceHandlerPath is the path of the diagnostic endpoint registered
+// by RegisterDebugKeyspaceHandler, mirroring pd-ctl's `keyspace-group` /
+// `show keyspace meta` commands for TiDB-side inspection.
+const DebugKeyspaceHandlerPath = "/debug/keyspace"
+
+// defaultTSOKeyspaceGroupID is the id of the default keyspace group that PD
+// bootstraps every cluster with; it uses a dedicated election key layout.
+const defaultTSOKeyspaceGroupID = "00000"
+
+var registerDebugKeyspaceHandlerOnce sync.Once
+
+// keyspaceDebugInfo is the JSON payload served by DebugKeyspaceHandlerPath.
+type keyspaceDebugInfo struct {
+	KeyspaceName     string    `json:"keyspace_name"`
+	KeyspaceID       string    `json:"keyspace_id"`
+	KeyspaceGroupID  string    `json:"keyspace_group_id"`
+	TSOPrimaryAddr   string    `json:"tso_primary_addr,omitempty"`
+	LastRefreshTime  time.Time `json:"last_refresh_time"`
+	LastRefreshError string    `json:"last_refresh_error,omitempty"`
+}
+
+// RegisterDebugKeyspaceHandler registers the /debug/keyspace endpoint on the
+// default ServeMux, reporting the keyspace and keyspace-group state that
+// RegisterMetrics / RegisterMetricsForBR resolved (and the refresher keeps
+// current), plus the TSO group's current primary address. It is idempotent,
+// so it's safe to call alongside RegisterMetrics on every invocation.
+func RegisterDebugKeyspaceHandler(etcdClient *clientv3.Client, clusterID uint64) {
+	registerDebugKeyspaceHandlerOnce.Do(func() {
+		http.HandleFunc(DebugKeyspaceHandlerPath, func(w http.ResponseWriter, r *http.Request) {
+			serveKeyspaceDebugInfo(w, etcdClient, clusterID)
+		})
+	})
+}
+
+func serveKeyspaceDebugInfo(w http.ResponseWriter, etcdClient *clientv3.Client, clusterID uint64) {
+	meta, groupID, ok := GetCachedKeyspaceMeta()
+	refreshedAt, refreshErr := GetCachedRefreshStatus()
+
+	info := keyspaceDebugInfo{
+		KeyspaceGroupID: groupID,
+		LastRefreshTime: refreshedAt,
+	}
+	if ok {
+		info.KeyspaceName = meta.GetName()
+		info.KeyspaceID = fmt.Sprint(meta.GetId())
+	}
+	if refreshErr != nil {
+		info.LastRefreshError = refreshErr.Error()
+	}
+
+	if etcdClient != nil {
+		addr, err := getTSOPrimaryAddr(etcdClient, clusterID, groupID)
+		if err != nil {
+			logutil.BgLogger().Warn("failed to resolve TSO keyspace group primary address", zap.Error(err))
+		} else {
+			info.TSOPrimaryAddr = addr
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		logutil.BgLogger().Warn("failed to encode keyspace debug info", zap.Error(err))
+	}
+}
+
+// getTSOPrimaryAddr reads the PD election key for the TSO keyspace group's
+// primary; see buildTSOElectionKey for the key shape.
+func getTSOPrimaryAddr(etcdClient *clientv3.Client, clusterID uint64, groupID string) (string, error) {
+	key, err := buildTSOElectionKey(clusterID, groupID)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	resp, err := etcdClient.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", fmt.Errorf("no primary found at %s", key)
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// buildTSOElectionKey builds the PD election key for groupID's TSO
+// primary, using the default group's distinct, shorter key shape when
+// groupID is empty, "0", or unresolved. Non-default group ids are
+// zero-padded to 5 digits to match PD's election-key layout.
+func buildTSOElectionKey(clusterID uint64, groupID string) (string, error) {
+	if groupID == "" || groupID == "0" {
+		return fmt.Sprintf("/ms/%d/tso/%s/primary", clusterID, defaultTSOKeyspaceGroupID), nil
+	}
+
+	id, err := strconv.ParseUint(groupID, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid keyspace group id %q: %w", groupID, err)
+	}
+	if id == 0 {
+		return fmt.Sprintf("/ms/%d/tso/%s/primary", clusterID, defaultTSOKeyspaceGroupID), nil
+	}
+	return fmt.Sprintf("/ms/%d/tso/keyspace_groups/election/%05d/primary", clusterID, id), nil
+}
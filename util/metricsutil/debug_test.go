@@ -0,0 +1,43 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsutil
+
+import "testing"
+
+func TestBuildTSOElectionKey(t *testing.T) {
+	cases := []struct {
+		name     string
+		groupID  string
+		wantKey  string
+		wantErr  bool
+	}{
+		{"empty group id uses default group key shape", "", "/ms/1/tso/00000/primary", false},
+		{"group id zero uses default group key shape", "0", "/ms/1/tso/00000/primary", false},
+		{"non-default group id is zero-padded", "5", "/ms/1/tso/keyspace_groups/election/00005/primary", false},
+		{"large group id", "12345", "/ms/1/tso/keyspace_groups/election/12345/primary", false},
+		{"non-numeric group id errors", "not-a-number", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := buildTSOElectionKey(1, c.groupID)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("buildTSOElectionKey(1, %q) error = %v, wantErr %v", c.groupID, err, c.wantErr)
+			}
+			if err == nil && got != c.wantKey {
+				t.Errorf("buildTSOElectionKey(1, %q) = %q, want %q", c.groupID, got, c.wantKey)
+			}
+		})
+	}
+}